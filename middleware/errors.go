@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// Sentinel errors returned by SessionManager.Parse/VerifyToken (and the
+// middleware built on top of them), always wrapped via fmt.Errorf("...: %w",
+// ...) so callers can use errors.Is instead of string-matching.
+var (
+	ErrTokenExpired          = errors.New("token is expired")
+	ErrTokenNotYetValid      = errors.New("token is not valid yet")
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenUnknownIssuer    = errors.New("token issuer is unknown")
+	ErrTokenRevoked          = errors.New("token has been revoked")
+	ErrNoTokenFound          = errors.New("no token found in request")
+)
+
+// classifyValidationError maps the bitmask on a *jwt.ValidationError onto our
+// sentinel taxonomy, preserving the original error via %w. Errors that aren't
+// a *jwt.ValidationError pass through as-is, as does a ValidationError whose
+// bitmask has none of these bits set (e.g. ValidationErrorUnverifiable, which
+// is how the parser wraps our own keyfunc errors; it unwraps to them).
+func classifyValidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var verr *jwt.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	switch {
+	case verr.Errors&jwt.ValidationErrorMalformed != 0:
+		return fmt.Errorf("%w: %s", ErrTokenMalformed, verr.Error())
+	case verr.Errors&jwt.ValidationErrorExpired != 0:
+		return fmt.Errorf("%w: %s", ErrTokenExpired, verr.Error())
+	case verr.Errors&jwt.ValidationErrorNotValidYet != 0:
+		return fmt.Errorf("%w: %s", ErrTokenNotYetValid, verr.Error())
+	case verr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+		return fmt.Errorf("%w: %s", ErrTokenSignatureInvalid, verr.Error())
+	default:
+		return verr
+	}
+}
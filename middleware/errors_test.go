@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func TestClassifyValidationError(t *testing.T) {
+	tests := []struct {
+		name   string
+		bitmap uint32
+		want   error
+	}{
+		{"malformed", jwt.ValidationErrorMalformed, ErrTokenMalformed},
+		{"expired", jwt.ValidationErrorExpired, ErrTokenExpired},
+		{"not valid yet", jwt.ValidationErrorNotValidYet, ErrTokenNotYetValid},
+		{"signature invalid", jwt.ValidationErrorSignatureInvalid, ErrTokenSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verr := &jwt.ValidationError{Errors: tt.bitmap}
+			got := classifyValidationError(verr)
+			if !errors.Is(got, tt.want) {
+				t.Fatalf("classifyValidationError(%v) = %v, want errors.Is match for %v", verr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyValidationErrorPassesThroughNonValidationErrors(t *testing.T) {
+	plain := errors.New("some other failure")
+	if got := classifyValidationError(plain); got != plain {
+		t.Fatalf("classifyValidationError() should pass through non-ValidationError errors unchanged, got %v", got)
+	}
+}
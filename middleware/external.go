@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// CreateForService mints a token for subject scoped to the named downstream
+// service (oidc.Settings.ExternalServices), embedding extraClaims alongside
+// the caller's identity and signing with that service's own key so it
+// verifies with a secret it alone holds, never the main session key. This
+// mirrors the EXTJWT pattern used to issue scoped tokens to sibling services.
+func (mgr *SessionManager) CreateForService(service, subject string, extraClaims map[string]interface{}) (string, error) {
+	cfg, ok := mgr.settings.ExternalServices[service]
+	if !ok {
+		return "", fmt.Errorf("no external service configured named %q", service)
+	}
+	rk, err := resolveKey(cfg.SigningKey)
+	if err != nil {
+		return "", fmt.Errorf("resolving signing key for service %q: %w", service, err)
+	}
+
+	issuer := cfg.Issuer
+	if issuer == "" {
+		issuer = SessionManagerClaimsIssuer
+	}
+	now := time.Now().UTC()
+
+	// Merge the caller's per-service claims first, then stamp the reserved
+	// identity/lifetime claims on top, so extraClaims can't clobber them
+	// (e.g. smuggling a different "sub" or a far-future "exp").
+	claims := jwt.MapClaims{}
+	for k, v := range extraClaims {
+		claims[k] = v
+	}
+	claims["sub"] = subject
+	claims["iss"] = issuer
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(mgr.accessTokenTTL).Unix()
+	if cfg.Audience != "" {
+		claims["aud"] = cfg.Audience
+	}
+
+	token := jwt.NewWithClaims(rk.method, claims)
+	token.Header["kid"] = rk.id
+	key, err := rk.signingKey()
+	if err != nil {
+		return "", err
+	}
+	return token.SignedString(key)
+}
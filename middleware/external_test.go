@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/devtron-labs/authenticator/oidc"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// TestCreateForServiceReservedClaimsWinOverExtraClaims is a regression test:
+// extraClaims must not be able to override the identity/lifetime claims
+// CreateForService establishes for the caller.
+func TestCreateForServiceReservedClaimsWinOverExtraClaims(t *testing.T) {
+	settings := &oidc.Settings{
+		SigningKeys: []oidc.SigningKey{
+			{ID: "active", Algorithm: oidc.HS256, Secret: []byte("session-secret")},
+		},
+		ActiveKeyID: "active",
+		ExternalServices: map[string]oidc.ExternalServiceConfig{
+			"image-host": {
+				SigningKey: oidc.SigningKey{ID: "image-host-key", Algorithm: oidc.HS256, Secret: []byte("image-host-secret")},
+				Audience:   "image-host",
+			},
+		},
+	}
+	mgr, err := NewSessionManager(settings, "")
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+
+	tokenString, err := mgr.CreateForService("image-host", "alice", map[string]interface{}{
+		"sub":     "mallory",
+		"aud":     "some-other-service",
+		"channel": "general",
+	})
+	if err != nil {
+		t.Fatalf("CreateForService: %v", err)
+	}
+
+	var claims jwt.MapClaims
+	if _, _, err := (&jwt.Parser{SkipClaimsValidation: true}).ParseUnverified(tokenString, &claims); err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	if claims["sub"] != "alice" {
+		t.Fatalf("sub = %v, want alice (extraClaims must not override identity)", claims["sub"])
+	}
+	if claims["aud"] != "image-host" {
+		t.Fatalf("aud = %v, want image-host (extraClaims must not override audience)", claims["aud"])
+	}
+	if claims["channel"] != "general" {
+		t.Fatalf("channel = %v, want general (non-reserved extraClaims should pass through)", claims["channel"])
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devtron-labs/authenticator/oidc"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func TestCheckFreshness(t *testing.T) {
+	mgr := &SessionManager{maxTokenAge: time.Minute, clockSkew: 5 * time.Second}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{"fresh", jwt.MapClaims{"iat": float64(now.Unix())}, false},
+		{"older than max age", jwt.MapClaims{"iat": float64(now.Add(-2 * time.Minute).Unix())}, true},
+		{"issued in the future beyond skew", jwt.MapClaims{"iat": float64(now.Add(time.Minute).Unix())}, true},
+		{"not valid yet beyond skew", jwt.MapClaims{"nbf": float64(now.Add(time.Minute).Unix())}, true},
+		{"expired beyond skew", jwt.MapClaims{"exp": float64(now.Add(-time.Minute).Unix())}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mgr.checkFreshness(tt.claims)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkFreshness() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParseAppliesFreshnessOnlyWhenRequested is a regression test for a bug
+// where the freshness check ran unconditionally: a refresh token (or any
+// token older than MaxTokenAge but not yet expired) must still verify by
+// default, and only be rejected when a caller opts in via
+// RequireFreshnessCheck.
+func TestParseAppliesFreshnessOnlyWhenRequested(t *testing.T) {
+	settings := &oidc.Settings{
+		SigningKeys: []oidc.SigningKey{
+			{ID: "active", Algorithm: oidc.HS256, Secret: []byte("session-secret")},
+		},
+		ActiveKeyID: "active",
+	}
+	mgr, err := NewSessionManager(settings, "", WithMaxTokenAge(time.Minute), WithClockSkew(0))
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+
+	// A refresh token issued an hour ago, well past MaxTokenAge, but still
+	// within its own TTL -- exactly the case that broke when the check was
+	// unconditional.
+	stale, err := mgr.signSessionToken("alice", tokenTypeRefresh, time.Now().Add(-time.Hour), mgr.refreshTokenTTL)
+	if err != nil {
+		t.Fatalf("signSessionToken: %v", err)
+	}
+
+	if _, err := mgr.Parse(stale); err != nil {
+		t.Fatalf("Parse should accept a stale-but-unexpired token by default, got: %v", err)
+	}
+	if _, err := mgr.Parse(stale, RequireFreshnessCheck()); err == nil {
+		t.Fatal("expected RequireFreshnessCheck to reject a token older than MaxTokenAge")
+	}
+}
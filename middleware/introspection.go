@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// introspectionResponse is the RFC 7662 introspection response, trimmed to
+// the fields we turn into claims.
+type introspectionResponse struct {
+	Active   bool   `json:"active"`
+	Subject  string `json:"sub"`
+	Username string `json:"username"`
+	Scope    string `json:"scope"`
+	Exp      int64  `json:"exp"`
+	Iat      int64  `json:"iat"`
+	Audience string `json:"aud"`
+}
+
+type introspectionCacheEntry struct {
+	claims    jwt.MapClaims
+	expiresAt time.Time
+}
+
+// introspectionCache caches positive introspection results by token hash, so
+// a hot path doesn't hit the IdP on every request.
+type introspectionCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]introspectionCacheEntry
+}
+
+func newIntrospectionCache() *introspectionCache {
+	return &introspectionCache{entries: make(map[[sha256.Size]byte]introspectionCacheEntry)}
+}
+
+func (c *introspectionCache) get(key [sha256.Size]byte) (jwt.MapClaims, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	if exp, ok := entry.claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(time.Now()) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.claims, true
+}
+
+func (c *introspectionCache) set(key [sha256.Size]byte, claims jwt.MapClaims, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = introspectionCacheEntry{claims: claims, expiresAt: expiresAt}
+}
+
+// introspectToken validates an opaque bearer token against the configured
+// OAuth2 introspection endpoint and synthesizes jwt.MapClaims from the
+// response so callers (and Username) can treat it like any other token.
+func (mgr *SessionManager) introspectToken(tokenString string) (jwt.Claims, error) {
+	cfg := mgr.settings.Introspection
+	if cfg == nil {
+		return nil, fmt.Errorf("%w: token is not a JWT and opaque token introspection is not configured", ErrTokenMalformed)
+	}
+
+	key := sha256.Sum256([]byte(tokenString))
+	if cfg.CacheTTL > 0 {
+		if claims, ok := mgr.introspectionCache.get(key); ok {
+			return claims, nil
+		}
+	}
+
+	form := url.Values{"token": {tokenString}}
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("building introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(cfg.ClientID, cfg.ClientSecret)
+
+	client := mgr.client
+	if cfg.Timeout > 0 {
+		clientCopy := *mgr.client
+		clientCopy.Timeout = cfg.Timeout
+		client = &clientCopy
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: introspection request failed: %s", ErrTokenSignatureInvalid, err)
+	}
+	defer resp.Body.Close()
+
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: decoding introspection response: %s", ErrTokenMalformed, err)
+	}
+	if !result.Active {
+		return nil, fmt.Errorf("%w: introspection reports token inactive", ErrTokenRevoked)
+	}
+	if result.Exp > 0 && time.Unix(result.Exp, 0).Before(time.Now()) {
+		return nil, fmt.Errorf("%w", ErrTokenExpired)
+	}
+
+	claims := jwt.MapClaims{}
+	if result.Subject != "" {
+		claims["sub"] = result.Subject
+	}
+	if result.Username != "" {
+		claims["username"] = result.Username
+	}
+	if result.Scope != "" {
+		claims["scope"] = result.Scope
+	}
+	if result.Exp > 0 {
+		claims["exp"] = float64(result.Exp)
+	}
+	if result.Iat > 0 {
+		claims["iat"] = float64(result.Iat)
+	}
+	if result.Audience != "" {
+		claims["aud"] = result.Audience
+	}
+
+	if cfg.CacheTTL > 0 {
+		expiresAt := time.Now().Add(cfg.CacheTTL)
+		if result.Exp > 0 {
+			if tokenExp := time.Unix(result.Exp, 0); tokenExp.Before(expiresAt) {
+				expiresAt = tokenExp
+			}
+		}
+		mgr.introspectionCache.set(key, claims, expiresAt)
+	}
+	return claims, nil
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devtron-labs/authenticator/oidc"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func newIntrospectionTestSessionManager(t *testing.T, introspectionURL string, cacheTTL time.Duration) *SessionManager {
+	t.Helper()
+	settings := &oidc.Settings{
+		SigningKeys: []oidc.SigningKey{
+			{ID: "active", Algorithm: oidc.HS256, Secret: []byte("session-secret")},
+		},
+		ActiveKeyID: "active",
+		Introspection: &oidc.IntrospectionConfig{
+			URL:          introspectionURL,
+			ClientID:     "client-id",
+			ClientSecret: "client-secret",
+			CacheTTL:     cacheTTL,
+		},
+	}
+	mgr, err := NewSessionManager(settings, "")
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+	return mgr
+}
+
+func TestIntrospectTokenRequestsBasicAuthAndSynthesizesClaims(t *testing.T) {
+	var gotUser, gotPass string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ok bool
+		gotUser, gotPass, ok = r.BasicAuth()
+		if !ok {
+			t.Fatal("expected introspection request to carry Basic auth")
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parsing introspection request body: %v", err)
+		}
+		if r.PostForm.Get("token") != "opaque-token" {
+			t.Fatalf("introspection request token = %q, want %q", r.PostForm.Get("token"), "opaque-token")
+		}
+		fmt.Fprintf(w, `{"active":true,"sub":"alice","username":"alice","scope":"profile","aud":"my-client","exp":%d}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer server.Close()
+
+	mgr := newIntrospectionTestSessionManager(t, server.URL, 0)
+
+	claims, err := mgr.introspectToken("opaque-token")
+	if err != nil {
+		t.Fatalf("introspectToken: %v", err)
+	}
+	if gotUser != "client-id" || gotPass != "client-secret" {
+		t.Fatalf("Basic auth = %q:%q, want %q:%q", gotUser, gotPass, "client-id", "client-secret")
+	}
+
+	mapClaims, ok := claims.(jwt.MapClaims)
+	if !ok {
+		t.Fatalf("introspectToken returned %T, want jwt.MapClaims", claims)
+	}
+	if mapClaims["sub"] != "alice" {
+		t.Fatalf("sub claim = %v, want %q", mapClaims["sub"], "alice")
+	}
+	if mapClaims["username"] != "alice" {
+		t.Fatalf("username claim = %v, want %q", mapClaims["username"], "alice")
+	}
+	if mapClaims["scope"] != "profile" {
+		t.Fatalf("scope claim = %v, want %q", mapClaims["scope"], "profile")
+	}
+}
+
+func TestIntrospectTokenRejectsInactiveAndExpiredTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"inactive", `{"active":false}`},
+		{"expired", fmt.Sprintf(`{"active":true,"sub":"alice","exp":%d}`, time.Now().Add(-time.Hour).Unix())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			mgr := newIntrospectionTestSessionManager(t, server.URL, 0)
+			if _, err := mgr.introspectToken("opaque-token"); err == nil {
+				t.Fatal("expected introspectToken to reject the token, got nil error")
+			}
+		})
+	}
+}
+
+// TestIntrospectTokenCacheDoesNotOutliveTokenExpiry is a regression test: a
+// CacheTTL longer than the token's own remaining lifetime must not keep
+// serving the token as valid past its "exp".
+func TestIntrospectTokenCacheDoesNotOutliveTokenExpiry(t *testing.T) {
+	requests := 0
+	// One fixed exp for the token, at least a fraction of a second ahead
+	// (exp has whole-second precision).
+	exp := time.Now().Add(time.Second).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"active":true,"sub":"alice","exp":%d}`, exp)
+	}))
+	defer server.Close()
+
+	mgr := newIntrospectionTestSessionManager(t, server.URL, time.Hour)
+
+	if _, err := mgr.introspectToken("opaque-token"); err != nil {
+		t.Fatalf("introspectToken: %v", err)
+	}
+	if _, err := mgr.introspectToken("opaque-token"); err != nil {
+		t.Fatalf("introspectToken (cache hit): %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected the second call to be served from cache, got %d upstream requests", requests)
+	}
+
+	time.Sleep(time.Until(time.Unix(exp, 0)) + 100*time.Millisecond)
+
+	if _, err := mgr.introspectToken("opaque-token"); err == nil {
+		t.Fatal("expected a cached entry to stop being honored once the token's own exp has passed")
+	}
+	if requests != 2 {
+		t.Fatalf("expected the expired cache entry to be re-checked upstream, got %d upstream requests", requests)
+	}
+}
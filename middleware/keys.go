@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/devtron-labs/authenticator/oidc"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// resolvedKey is a SigningKey with its PEM material parsed into the key types
+// the jwt library expects. The session key set (newKeySet) is resolved once up
+// front so session signing/verification don't re-parse PEM on every request;
+// CreateForService resolves the service's key on each call.
+type resolvedKey struct {
+	id        string
+	algorithm oidc.SigningAlgorithm
+	method    jwt.SigningMethod
+
+	hmacSecret []byte
+	rsaPrivate *rsa.PrivateKey
+	rsaPublic  *rsa.PublicKey
+	edPrivate  ed25519.PrivateKey
+	edPublic   ed25519.PublicKey
+}
+
+// signingKey returns the key material to pass to jwt.Token.SignedString.
+func (k *resolvedKey) signingKey() (interface{}, error) {
+	switch k.algorithm {
+	case oidc.HS256:
+		return k.hmacSecret, nil
+	case oidc.RS256:
+		if k.rsaPrivate == nil {
+			return nil, fmt.Errorf("key %q has no private key material configured", k.id)
+		}
+		return k.rsaPrivate, nil
+	case oidc.EdDSA:
+		if k.edPrivate == nil {
+			return nil, fmt.Errorf("key %q has no private key material configured", k.id)
+		}
+		return k.edPrivate, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", k.algorithm)
+	}
+}
+
+// verifyingKey returns the key material to pass to the jwt parser's keyfunc.
+func (k *resolvedKey) verifyingKey() (interface{}, error) {
+	switch k.algorithm {
+	case oidc.HS256:
+		return k.hmacSecret, nil
+	case oidc.RS256:
+		if k.rsaPublic != nil {
+			return k.rsaPublic, nil
+		}
+		if k.rsaPrivate != nil {
+			return &k.rsaPrivate.PublicKey, nil
+		}
+		return nil, fmt.Errorf("key %q has no public key material configured", k.id)
+	case oidc.EdDSA:
+		if k.edPublic != nil {
+			return k.edPublic, nil
+		}
+		if k.edPrivate != nil {
+			return k.edPrivate.Public().(ed25519.PublicKey), nil
+		}
+		return nil, fmt.Errorf("key %q has no public key material configured", k.id)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", k.algorithm)
+	}
+}
+
+// keySet resolves oidc.Settings.SigningKeys into ready-to-use signing and
+// verifying material, indexed by kid.
+type keySet struct {
+	keys     map[string]*resolvedKey
+	activeID string
+}
+
+func newKeySet(settings *oidc.Settings) (*keySet, error) {
+	ks := &keySet{keys: make(map[string]*resolvedKey, len(settings.SigningKeys))}
+	for _, def := range settings.SigningKeys {
+		rk, err := resolveKey(def)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", def.ID, err)
+		}
+		ks.keys[def.ID] = rk
+	}
+	if settings.ActiveKeyID == "" {
+		return nil, fmt.Errorf("oidc.Settings.ActiveKeyID must name one of SigningKeys")
+	}
+	if _, ok := ks.keys[settings.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q not found in SigningKeys", settings.ActiveKeyID)
+	}
+	ks.activeID = settings.ActiveKeyID
+	return ks, nil
+}
+
+func resolveKey(def oidc.SigningKey) (*resolvedKey, error) {
+	rk := &resolvedKey{id: def.ID, algorithm: def.Algorithm}
+	switch def.Algorithm {
+	case oidc.HS256:
+		if len(def.Secret) == 0 {
+			return nil, fmt.Errorf("HS256 key requires Secret")
+		}
+		rk.hmacSecret = def.Secret
+		rk.method = jwt.SigningMethodHS256
+	case oidc.RS256:
+		rk.method = jwt.SigningMethodRS256
+		if len(def.PrivateKeyPEM) > 0 {
+			key, err := jwt.ParseRSAPrivateKeyFromPEM(def.PrivateKeyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("parsing RSA private key: %w", err)
+			}
+			rk.rsaPrivate = key
+		}
+		if len(def.PublicKeyPEM) > 0 {
+			key, err := jwt.ParseRSAPublicKeyFromPEM(def.PublicKeyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("parsing RSA public key: %w", err)
+			}
+			rk.rsaPublic = key
+		}
+		if rk.rsaPrivate == nil && rk.rsaPublic == nil {
+			return nil, fmt.Errorf("RS256 key requires PrivateKeyPEM or PublicKeyPEM")
+		}
+	case oidc.EdDSA:
+		rk.method = jwt.SigningMethodEdDSA
+		if len(def.PrivateKeyPEM) > 0 {
+			key, err := parseEd25519PrivateKeyFromPEM(def.PrivateKeyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("parsing Ed25519 private key: %w", err)
+			}
+			rk.edPrivate = key
+		}
+		if len(def.PublicKeyPEM) > 0 {
+			key, err := parseEd25519PublicKeyFromPEM(def.PublicKeyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("parsing Ed25519 public key: %w", err)
+			}
+			rk.edPublic = key
+		}
+		if rk.edPrivate == nil && rk.edPublic == nil {
+			return nil, fmt.Errorf("EdDSA key requires PrivateKeyPEM or PublicKeyPEM")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", def.Algorithm)
+	}
+	return rk, nil
+}
+
+func (ks *keySet) active() *resolvedKey {
+	return ks.keys[ks.activeID]
+}
+
+func (ks *keySet) byID(kid string) (*resolvedKey, error) {
+	rk, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return rk, nil
+}
+
+// the jwt library has no PKCS8-aware Ed25519 PEM helpers, so parse it directly.
+func parseEd25519PrivateKeyFromPEM(pemBytes []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 private key")
+	}
+	return edKey, nil
+}
+
+func parseEd25519PublicKeyFromPEM(pemBytes []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 public key")
+	}
+	return edKey, nil
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/devtron-labs/authenticator/oidc"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func generateRSAKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func generateEd25519KeyPEM(t *testing.T) []byte {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating Ed25519 key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshaling Ed25519 key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestResolveKeySigningAndVerifying(t *testing.T) {
+	tests := []struct {
+		name string
+		def  oidc.SigningKey
+	}{
+		{"HS256", oidc.SigningKey{ID: "hmac-1", Algorithm: oidc.HS256, Secret: []byte("super-secret")}},
+		{"RS256", oidc.SigningKey{ID: "rsa-1", Algorithm: oidc.RS256, PrivateKeyPEM: generateRSAKeyPEM(t)}},
+		{"EdDSA", oidc.SigningKey{ID: "ed-1", Algorithm: oidc.EdDSA, PrivateKeyPEM: generateEd25519KeyPEM(t)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rk, err := resolveKey(tt.def)
+			if err != nil {
+				t.Fatalf("resolveKey: %v", err)
+			}
+			if _, err := rk.signingKey(); err != nil {
+				t.Fatalf("signingKey: %v", err)
+			}
+			if _, err := rk.verifyingKey(); err != nil {
+				t.Fatalf("verifyingKey: %v", err)
+			}
+		})
+	}
+}
+
+// TestParseRejectsAlgConfusion reproduces the classic RS256->HS256
+// alg-confusion attack: an attacker who only knows the (public) RSA
+// verifying key tries to forge an HS256 token using that public key as the
+// HMAC secret, under the same kid an RS256 key would use. Parse must reject
+// it because the token's alg doesn't match the resolved key's algorithm.
+func TestParseRejectsAlgConfusion(t *testing.T) {
+	settings := &oidc.Settings{
+		SigningKeys: []oidc.SigningKey{
+			{ID: "active", Algorithm: oidc.RS256, PrivateKeyPEM: generateRSAKeyPEM(t)},
+		},
+		ActiveKeyID: "active",
+	}
+	mgr, err := NewSessionManager(settings, "")
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+
+	rk, err := mgr.keys.byID("active")
+	if err != nil {
+		t.Fatalf("byID: %v", err)
+	}
+	pub, err := rk.verifyingKey()
+	if err != nil {
+		t.Fatalf("verifyingKey: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub.(*rsa.PublicKey))
+	if err != nil {
+		t.Fatalf("marshaling public key: %v", err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "mallory",
+		"iss": SessionManagerClaimsIssuer,
+	})
+	forged.Header["kid"] = "active"
+	forgedString, err := forged.SignedString(pubDER)
+	if err != nil {
+		t.Fatalf("signing forged token: %v", err)
+	}
+
+	if _, err := mgr.Parse(forgedString); err == nil {
+		t.Fatal("expected Parse to reject an alg-confused token")
+	}
+}
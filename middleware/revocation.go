@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// RevocationStore tracks invalidated tokens so VerifyToken/Parse can reject
+// them even though they haven't expired yet. It backs both single-token
+// revocation (logout, refresh rotation) and subject-wide revocation (password
+// change), replacing the old AdminPasswordMtime comparison with a real,
+// pluggable mechanism.
+type RevocationStore interface {
+	// Revoke marks jti as revoked. expiresAt is the token's own expiry, so the
+	// store can drop the entry once the token would no longer be accepted
+	// anyway.
+	Revoke(jti string, expiresAt time.Time)
+
+	// IsRevoked reports whether jti has been revoked.
+	IsRevoked(jti string) bool
+
+	// RevokeSubject invalidates every token for subject issued at or before
+	// "before" (typically time.Now()).
+	RevokeSubject(subject string, before time.Time)
+
+	// SubjectRevokedBefore returns the cutoff previously set by RevokeSubject,
+	// if any.
+	SubjectRevokedBefore(subject string) (time.Time, bool)
+}
+
+// memoryRevocationStore is the default in-memory RevocationStore. It is only
+// suitable for a single-replica deployment; a multi-replica deployment should
+// supply a shared implementation (e.g. backed by Redis) via
+// WithRevocationStore.
+type memoryRevocationStore struct {
+	mu       sync.Mutex
+	jtis     map[string]time.Time // jti -> expiresAt
+	subjects map[string]time.Time // subject -> revoked-before cutoff
+}
+
+// NewMemoryRevocationStore creates the default in-memory RevocationStore.
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{
+		jtis:     make(map[string]time.Time),
+		subjects: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryRevocationStore) Revoke(jti string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	s.jtis[jti] = expiresAt
+}
+
+func (s *memoryRevocationStore) IsRevoked(jti string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.jtis[jti]
+	return ok
+}
+
+func (s *memoryRevocationStore) RevokeSubject(subject string, before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subjects[subject] = before
+}
+
+func (s *memoryRevocationStore) SubjectRevokedBefore(subject string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.subjects[subject]
+	return t, ok
+}
+
+// prune drops jtis whose own expiry has already passed; callers hold s.mu.
+func (s *memoryRevocationStore) prune() {
+	now := time.Now()
+	for jti, exp := range s.jtis {
+		if now.After(exp) {
+			delete(s.jtis, jti)
+		}
+	}
+}
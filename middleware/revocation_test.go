@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/devtron-labs/authenticator/oidc"
+)
+
+func newTestSessionManager(t *testing.T, opts ...SessionManagerOption) *SessionManager {
+	t.Helper()
+	settings := &oidc.Settings{
+		SigningKeys: []oidc.SigningKey{
+			{ID: "active", Algorithm: oidc.HS256, Secret: []byte("session-secret")},
+		},
+		ActiveKeyID: "active",
+	}
+	mgr, err := NewSessionManager(settings, "", opts...)
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+	return mgr
+}
+
+func TestRefreshRotatesAndRevokesThePresentedToken(t *testing.T) {
+	mgr := newTestSessionManager(t)
+
+	_, refresh, err := mgr.CreateTokenPair("alice")
+	if err != nil {
+		t.Fatalf("CreateTokenPair: %v", err)
+	}
+
+	newAccess, newRefresh, err := mgr.Refresh(refresh)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if newAccess == "" || newRefresh == "" {
+		t.Fatal("Refresh returned an empty token")
+	}
+	if newRefresh == refresh {
+		t.Fatal("Refresh must rotate the refresh token, not return the same one")
+	}
+
+	if _, err := mgr.VerifyToken(newAccess); err != nil {
+		t.Fatalf("new access token should verify, got: %v", err)
+	}
+
+	if _, _, err := mgr.Refresh(refresh); err == nil {
+		t.Fatal("a rotated-out refresh token must be rejected on reuse")
+	}
+}
+
+func TestRevokeRejectsFurtherUse(t *testing.T) {
+	mgr := newTestSessionManager(t)
+
+	access, _, err := mgr.CreateTokenPair("alice")
+	if err != nil {
+		t.Fatalf("CreateTokenPair: %v", err)
+	}
+	if _, err := mgr.VerifyToken(access); err != nil {
+		t.Fatalf("access token should verify before revocation, got: %v", err)
+	}
+
+	if err := mgr.Revoke(access); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := mgr.VerifyToken(access); err == nil {
+		t.Fatal("expected a revoked token to be rejected")
+	}
+}
+
+func TestRevokeAllForSubjectRejectsExistingTokens(t *testing.T) {
+	mgr := newTestSessionManager(t)
+
+	access, _, err := mgr.CreateTokenPair("alice")
+	if err != nil {
+		t.Fatalf("CreateTokenPair: %v", err)
+	}
+
+	mgr.RevokeAllForSubject("alice")
+
+	if _, err := mgr.VerifyToken(access); err == nil {
+		t.Fatal("expected a token issued before RevokeAllForSubject to be rejected")
+	}
+
+	// A different subject's tokens are unaffected.
+	bobAccess, _, err := mgr.CreateTokenPair("bob")
+	if err != nil {
+		t.Fatalf("CreateTokenPair: %v", err)
+	}
+	if _, err := mgr.VerifyToken(bobAccess); err != nil {
+		t.Fatalf("expected bob's token to remain valid, got: %v", err)
+	}
+}
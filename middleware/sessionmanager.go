@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	jwt2 "github.com/devtron-labs/authenticator/jwt"
 	"github.com/devtron-labs/authenticator/oidc"
@@ -17,6 +19,16 @@ type SessionManager struct {
 	settings *oidc.Settings
 	client   *http.Client
 	prov     oidc.Provider
+	keys     *keySet
+
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	revocation      RevocationStore
+
+	introspectionCache *introspectionCache
+
+	maxTokenAge time.Duration
+	clockSkew   time.Duration
 }
 
 const (
@@ -27,12 +39,78 @@ const (
 	invalidLoginError  = "Invalid username or password"
 	blankPasswordError = "Blank passwords are not allowed"
 	badUserError       = "Bad local superuser username"
+
+	// tokenTypeAccess/tokenTypeRefresh distinguish the two halves of a token
+	// pair via the "typ" claim.
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+
+	// DefaultAccessTokenTTL/DefaultRefreshTokenTTL are used when
+	// WithAccessTokenTTL/WithRefreshTokenTTL aren't passed to NewSessionManager.
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// DefaultMaxTokenAge/DefaultClockSkew bound how old a token's "iat" may be
+	// and how much clock drift between issuer and verifier is tolerated. They
+	// guard against replay of an old-but-not-yet-expired token.
+	DefaultMaxTokenAge = 60 * time.Second
+	DefaultClockSkew   = 5 * time.Second
 )
 
+// sessionClaims is jwt.StandardClaims plus the "typ" claim used to tell an
+// access token from a refresh token.
+type sessionClaims struct {
+	jwt.StandardClaims
+	Type string `json:"typ,omitempty"`
+}
+
+// SessionManagerOption customizes a SessionManager at construction time.
+type SessionManagerOption func(*SessionManager)
+
+// WithAccessTokenTTL overrides DefaultAccessTokenTTL.
+func WithAccessTokenTTL(ttl time.Duration) SessionManagerOption {
+	return func(mgr *SessionManager) { mgr.accessTokenTTL = ttl }
+}
+
+// WithRefreshTokenTTL overrides DefaultRefreshTokenTTL.
+func WithRefreshTokenTTL(ttl time.Duration) SessionManagerOption {
+	return func(mgr *SessionManager) { mgr.refreshTokenTTL = ttl }
+}
+
+// WithRevocationStore overrides the default in-memory RevocationStore, e.g.
+// with one shared across replicas.
+func WithRevocationStore(store RevocationStore) SessionManagerOption {
+	return func(mgr *SessionManager) { mgr.revocation = store }
+}
+
+// WithMaxTokenAge overrides DefaultMaxTokenAge.
+func WithMaxTokenAge(d time.Duration) SessionManagerOption {
+	return func(mgr *SessionManager) { mgr.maxTokenAge = d }
+}
+
+// WithClockSkew overrides DefaultClockSkew.
+func WithClockSkew(d time.Duration) SessionManagerOption {
+	return func(mgr *SessionManager) { mgr.clockSkew = d }
+}
+
 // NewSessionManager creates a new session manager from Argo CD settings
-func NewSessionManager(settings *oidc.Settings, dexServerAddr string) *SessionManager {
+func NewSessionManager(settings *oidc.Settings, dexServerAddr string, opts ...SessionManagerOption) (*SessionManager, error) {
+	keys, err := newKeySet(settings)
+	if err != nil {
+		return nil, fmt.Errorf("building session manager key set: %w", err)
+	}
 	s := SessionManager{
-		settings: settings,
+		settings:           settings,
+		keys:               keys,
+		accessTokenTTL:     DefaultAccessTokenTTL,
+		refreshTokenTTL:    DefaultRefreshTokenTTL,
+		revocation:         NewMemoryRevocationStore(),
+		introspectionCache: newIntrospectionCache(),
+		maxTokenAge:        DefaultMaxTokenAge,
+		clockSkew:          DefaultClockSkew,
+	}
+	for _, opt := range opts {
+		opt(&s)
 	}
 	s.client = &http.Client{
 		Transport: &http.Transport{
@@ -47,7 +125,16 @@ func NewSessionManager(settings *oidc.Settings, dexServerAddr string) *SessionMa
 		},
 	}
 	s.client.Transport = oidc.NewDexRewriteURLRoundTripper(dexServerAddr, s.client.Transport)
-	return &s
+	return &s, nil
+}
+
+// newJTI generates a random token identifier for the "jti" claim.
+func newJTI() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating jti: %w", err)
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // Create creates a new token for a given subject (user) and returns it as a string.
@@ -70,68 +157,255 @@ func (mgr *SessionManager) Create(subject string, secondsBeforeExpiry int64) (st
 	return mgr.signClaims(claims)
 }
 
+// CreateTokenPair issues a short-lived access token and a longer-lived
+// refresh token for subject, distinguished by their "typ" claim.
+func (mgr *SessionManager) CreateTokenPair(subject string) (access string, refresh string, err error) {
+	now := time.Now().UTC()
+	access, err = mgr.signSessionToken(subject, tokenTypeAccess, now, mgr.accessTokenTTL)
+	if err != nil {
+		return "", "", fmt.Errorf("creating access token: %w", err)
+	}
+	refresh, err = mgr.signSessionToken(subject, tokenTypeRefresh, now, mgr.refreshTokenTTL)
+	if err != nil {
+		return "", "", fmt.Errorf("creating refresh token: %w", err)
+	}
+	return access, refresh, nil
+}
+
+func (mgr *SessionManager) signSessionToken(subject, typ string, issuedAt time.Time, ttl time.Duration) (string, error) {
+	jti, err := newJTI()
+	if err != nil {
+		return "", err
+	}
+	claims := sessionClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  issuedAt.Unix(),
+			Issuer:    SessionManagerClaimsIssuer,
+			NotBefore: issuedAt.Unix(),
+			Subject:   subject,
+			ExpiresAt: issuedAt.Add(ttl).Unix(),
+		},
+		Type: typ,
+	}
+	return mgr.signClaims(claims)
+}
+
+// Refresh validates refreshToken, rotates it (the presented refresh JTI is
+// revoked and a new one issued), and returns a fresh access/refresh pair.
+func (mgr *SessionManager) Refresh(refreshToken string) (newAccess string, newRefresh string, err error) {
+	claims, err := mgr.VerifyToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	mapClaims, err := jwt2.MapClaims(claims)
+	if err != nil {
+		return "", "", err
+	}
+	if jwt2.GetField(mapClaims, "typ") != tokenTypeRefresh {
+		return "", "", fmt.Errorf("%w: token is not a refresh token", ErrTokenMalformed)
+	}
+	jti := jwt2.GetField(mapClaims, "jti")
+	subject := jwt2.GetField(mapClaims, "sub")
+	expUnix, _ := mapClaims["exp"].(float64)
+	mgr.revocation.Revoke(jti, time.Unix(int64(expUnix), 0))
+
+	return mgr.CreateTokenPair(subject)
+}
+
+// Revoke invalidates tokenString so it is rejected by VerifyToken/Parse even
+// before it expires, e.g. on logout.
+func (mgr *SessionManager) Revoke(tokenString string) error {
+	claims, err := mgr.VerifyToken(tokenString)
+	if err != nil {
+		return err
+	}
+	mapClaims, err := jwt2.MapClaims(claims)
+	if err != nil {
+		return err
+	}
+	jti := jwt2.GetField(mapClaims, "jti")
+	if jti == "" {
+		return fmt.Errorf("%w: token has no jti claim to revoke", ErrTokenMalformed)
+	}
+	expUnix, _ := mapClaims["exp"].(float64)
+	mgr.revocation.Revoke(jti, time.Unix(int64(expUnix), 0))
+	return nil
+}
+
+// RevokeAllForSubject invalidates every token issued for subject up to now,
+// e.g. on a password change, without needing to know their individual jtis.
+func (mgr *SessionManager) RevokeAllForSubject(subject string) {
+	mgr.revocation.RevokeSubject(subject, time.Now())
+}
+
 func (mgr *SessionManager) signClaims(claims jwt.Claims) (string, error) {
 	log.Infof("Issuing claims: %v", claims)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(mgr.settings.OIDCConfig.ClientSecret)
+	active := mgr.keys.active()
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.id
+	key, err := active.signingKey()
+	if err != nil {
+		return "", err
+	}
+	return token.SignedString(key)
+}
+
+// ParseOption customizes a single Parse/VerifyToken call.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	requireFreshness bool
+}
+
+// RequireFreshnessCheck opts a call into the iat/nbf/MaxTokenAge/ClockSkew
+// freshness check. It's off by default: ordinary access/refresh tokens carry
+// TTLs (minutes to weeks) far longer than MaxTokenAge's 60s default, so
+// applying the check there would reject them almost immediately after
+// issuance. Pass this for high-security auth-handshake flows that need to
+// reject replay of an old-but-not-yet-expired token.
+func RequireFreshnessCheck() ParseOption {
+	return func(o *parseOptions) { o.requireFreshness = true }
 }
 
 // Parse tries to parse the provided string and returns the token claims for local superuser login.
-func (mgr *SessionManager) Parse(tokenString string) (jwt.Claims, error) {
+func (mgr *SessionManager) Parse(tokenString string, opts ...ParseOption) (jwt.Claims, error) {
+	cfg := parseOptions{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 	// Parse takes the token string and a function for looking up the key. The latter is especially
 	// useful if you use multiple keys for your application.  The standard is to use 'kid' in the
 	// head of the token to identify which key to use, but the parsed token (head and claims) is provided
 	// to the callback, providing flexibility.
 	var claims jwt.MapClaims
-	settings := mgr.settings
 
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		// Don't forget to validate the alg is what you expect:
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		kid, ok := token.Header["kid"].(string)
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("%w: token has no kid header", ErrTokenMalformed)
 		}
-		return settings.OIDCConfig.ClientSecret, nil
+		rk, err := mgr.keys.byID(kid)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrTokenSignatureInvalid, err)
+		}
+		// Validate the resolved key's algorithm matches what the token claims to
+		// be signed with, rather than just checking the HMAC family, so a token
+		// can't swap alg to coerce a different key's material (alg-confusion).
+		if token.Method.Alg() != rk.method.Alg() {
+			return nil, fmt.Errorf("%w: unexpected signing method %q for key %q", ErrTokenSignatureInvalid, token.Method.Alg(), kid)
+		}
+		return rk.verifyingKey()
 	})
 	if err != nil {
-		return nil, err
+		return nil, classifyValidationError(err)
+	}
+	if jti, ok := claims["jti"].(string); ok && jti != "" && mgr.revocation.IsRevoked(jti) {
+		return nil, fmt.Errorf("%w", ErrTokenRevoked)
+	}
+	if sub, ok := claims["sub"].(string); ok {
+		if revokedBefore, ok := mgr.revocation.SubjectRevokedBefore(sub); ok {
+			if iat, ok := claims["iat"].(float64); ok && !time.Unix(int64(iat), 0).After(revokedBefore) {
+				return nil, fmt.Errorf("%w", ErrTokenRevoked)
+			}
+		}
+	}
+	if cfg.requireFreshness {
+		if err := mgr.checkFreshness(claims); err != nil {
+			return nil, err
+		}
 	}
-	/*
-		issuedAt := time.Unix(int64(claims["iat"].(float64)), 0)
-		if issuedAt.Before(settings.AdminPasswordMtime) {
-			return nil, fmt.Errorf("Password for superuser has changed since token issued")
-		}*/
 	return token.Claims, nil
 }
 
+// checkFreshness enforces that "iat" lies within
+// [now - maxTokenAge - clockSkew, now + clockSkew], and that "nbf"/"exp" are
+// honored with the same skew tolerance. This guards against replay of an old
+// but not-yet-expired token, beyond what the jwt library checks by default.
+func (mgr *SessionManager) checkFreshness(claims jwt.MapClaims) error {
+	now := time.Now()
+	if iat, ok := claims["iat"].(float64); ok {
+		issuedAt := time.Unix(int64(iat), 0)
+		if issuedAt.Before(now.Add(-mgr.maxTokenAge - mgr.clockSkew)) {
+			return fmt.Errorf("%w: token is older than the configured max age", ErrTokenExpired)
+		}
+		if issuedAt.After(now.Add(mgr.clockSkew)) {
+			return fmt.Errorf("%w: token issued in the future", ErrTokenNotYetValid)
+		}
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if time.Unix(int64(nbf), 0).After(now.Add(mgr.clockSkew)) {
+			return fmt.Errorf("%w", ErrTokenNotYetValid)
+		}
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		if time.Unix(int64(exp), 0).Before(now.Add(-mgr.clockSkew)) {
+			return fmt.Errorf("%w", ErrTokenExpired)
+		}
+	}
+	return nil
+}
+
 // VerifyToken verifies if a token is correct. Tokens can be issued either from us or by an IDP.
-// We choose how to verify based on the issuer.
-func (mgr *SessionManager) VerifyToken(tokenString string) (jwt.Claims, error) {
+// We choose how to verify based on the issuer. The iat/nbf freshness check is
+// off by default (see RequireFreshnessCheck) and is never run for the
+// OIDC-signed branch, since there the IdP owns lifetime policy.
+func (mgr *SessionManager) VerifyToken(tokenString string, opts ...ParseOption) (jwt.Claims, error) {
 	parser := &jwt.Parser{
 		SkipClaimsValidation: true,
 	}
 	var claims jwt.RegisteredClaims
 	_, _, err := parser.ParseUnverified(tokenString, &claims)
 	if err != nil {
-		return nil, err
+		// Not a parseable JWT at all; it may be an opaque token meant for
+		// introspection instead.
+		if mgr.settings.Introspection != nil {
+			return mgr.introspectToken(tokenString)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrTokenMalformed, err)
 	}
-	switch claims.Issuer {
-	case SessionManagerClaimsIssuer:
+	switch {
+	case claims.Issuer == SessionManagerClaimsIssuer:
 		// Argo CD signed token
-		return mgr.Parse(tokenString)
+		return mgr.Parse(tokenString, opts...)
+	case claims.Issuer != "" && claims.Issuer == mgr.idpIssuer():
+		// An empty/missing "iss" must never match here even if no IDP is
+		// configured (mgr.idpIssuer() == ""): that's an unknown issuer, not
+		// "the IDP we trust", and routing it into verifyIDPToken would nil-deref
+		// on mgr.settings.OIDCConfig.Issuer.
+		return mgr.verifyIDPToken(claims, tokenString)
 	default:
-		// IDP signed token
-		prov, err := mgr.provider()
-		if err != nil {
-			return nil, err
-		}
-		idToken, err := prov.Verify(claims.Audience[0], tokenString)
-		if err != nil {
-			return nil, err
+		// Neither our own issuer nor the configured IDP: fall back to
+		// introspection if configured, otherwise it's untrusted.
+		if mgr.settings.Introspection != nil {
+			return mgr.introspectToken(tokenString)
 		}
-		var claims jwt.MapClaims
-		err = idToken.Claims(&claims)
-		return claims, err
+		return nil, fmt.Errorf("%w: %q", ErrTokenUnknownIssuer, claims.Issuer)
+	}
+}
+
+// idpIssuer returns the configured OIDC issuer, or "" if none is configured.
+func (mgr *SessionManager) idpIssuer() string {
+	if mgr.settings.OIDCConfig == nil {
+		return ""
 	}
+	return mgr.settings.OIDCConfig.Issuer
+}
+
+func (mgr *SessionManager) verifyIDPToken(claims jwt.RegisteredClaims, tokenString string) (jwt.Claims, error) {
+	prov, err := mgr.provider()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTokenUnknownIssuer, err)
+	}
+	idToken, err := prov.Verify(claims.Audience[0], tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTokenSignatureInvalid, err)
+	}
+	var idClaims jwt.MapClaims
+	if err := idToken.Claims(&idClaims); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTokenMalformed, err)
+	}
+	return idClaims, nil
 }
 
 func (mgr *SessionManager) provider() (oidc.Provider, error) {
@@ -144,7 +418,7 @@ func (mgr *SessionManager) provider() (oidc.Provider, error) {
 
 // Username is a helper to extract a human readable username from a context
 func Username(ctx context.Context) string {
-	claims, ok := ctx.Value("claims").(jwt.Claims)
+	claims, ok := ctx.Value(ClaimsContextKey).(jwt.Claims)
 	if !ok {
 		return ""
 	}
@@ -156,6 +430,14 @@ func Username(ctx context.Context) string {
 	case SessionManagerClaimsIssuer:
 		return jwt2.GetField(mapClaims, "sub")
 	default:
-		return jwt2.GetField(mapClaims, "email")
+		// OIDC tokens carry "email"; opaque tokens resolved via introspection
+		// carry "username" instead and have no "iss" claim at all.
+		if email := jwt2.GetField(mapClaims, "email"); email != "" {
+			return email
+		}
+		if username := jwt2.GetField(mapClaims, "username"); username != "" {
+			return username
+		}
+		return jwt2.GetField(mapClaims, "sub")
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// contextKey avoids collisions with context values set by other packages.
+type contextKey string
+
+const (
+	// ClaimsContextKey is where Verifier stashes the verified jwt.Claims.
+	ClaimsContextKey contextKey = "claims"
+	// ClaimsErrorContextKey is where Verifier stashes the error explaining why
+	// no claims could be produced for the request.
+	ClaimsErrorContextKey contextKey = "claimsError"
+)
+
+// DefaultTokenCookieName is the cookie Verifier looks for when no bearer
+// token is present in the Authorization header.
+const DefaultTokenCookieName = "token"
+
+// VerifierOption customizes Verifier.
+type VerifierOption func(*verifierConfig)
+
+type verifierConfig struct {
+	cookieName string
+}
+
+// WithCookieName overrides DefaultTokenCookieName.
+func WithCookieName(name string) VerifierOption {
+	return func(c *verifierConfig) { c.cookieName = name }
+}
+
+// Verifier returns middleware that looks for a JWT in the Authorization
+// header, a cookie, or a query parameter (in that order), verifies it via
+// mgr.VerifyToken, and stashes the result in the request context under
+// ClaimsContextKey/ClaimsErrorContextKey for downstream handlers such as
+// Authenticator and Username.
+func Verifier(mgr *SessionManager, opts ...VerifierOption) func(http.Handler) http.Handler {
+	cfg := verifierConfig{cookieName: DefaultTokenCookieName}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			tokenString, err := extractToken(r, cfg.cookieName)
+			if err != nil {
+				ctx = context.WithValue(ctx, ClaimsErrorContextKey, err)
+			} else if claims, err := mgr.VerifyToken(tokenString); err != nil {
+				ctx = context.WithValue(ctx, ClaimsErrorContextKey, err)
+			} else {
+				ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// extractToken finds a bearer token in the request, checking the
+// Authorization header, then the named cookie, then the "jwt" query
+// parameter.
+func extractToken(r *http.Request, cookieName string) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") && parts[1] != "" {
+			return parts[1], nil
+		}
+	}
+	if cookie, err := r.Cookie(cookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	if token := r.URL.Query().Get("jwt"); token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("%w: checked Authorization header, %q cookie, and jwt query parameter", ErrNoTokenFound, cookieName)
+}
+
+// Authenticator short-circuits with 401 when Verifier couldn't produce valid
+// claims for the request, distinguishing why so callers see a message that
+// matches the failure (expired vs. malformed vs. simply absent).
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err, ok := r.Context().Value(ClaimsErrorContextKey).(error); ok && err != nil {
+			http.Error(w, authenticatorMessage(err), http.StatusUnauthorized)
+			return
+		}
+		if r.Context().Value(ClaimsContextKey) == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authenticatorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrNoTokenFound):
+		return "unauthorized: no token supplied"
+	case errors.Is(err, ErrTokenExpired):
+		return "unauthorized: token is expired"
+	case errors.Is(err, ErrTokenNotYetValid):
+		return "unauthorized: token is not valid yet"
+	case errors.Is(err, ErrTokenRevoked):
+		return "unauthorized: token has been revoked"
+	case errors.Is(err, ErrTokenMalformed):
+		return "unauthorized: token is malformed"
+	case errors.Is(err, ErrTokenSignatureInvalid), errors.Is(err, ErrTokenUnknownIssuer):
+		return "unauthorized: token signature could not be verified"
+	default:
+		return "unauthorized"
+	}
+}
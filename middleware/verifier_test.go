@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractTokenPrecedence(t *testing.T) {
+	const cookieName = DefaultTokenCookieName
+
+	tests := []struct {
+		name    string
+		header  string
+		cookie  string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"header wins over cookie and query", "Bearer header-token", "cookie-token", "query-token", "header-token", false},
+		{"cookie wins over query when no header", "", "cookie-token", "query-token", "cookie-token", false},
+		{"query used when neither header nor cookie present", "", "", "query-token", "query-token", false},
+		{"no token anywhere is an error", "", "", "", "", true},
+		{"malformed header without Bearer scheme falls through", "header-token", "cookie-token", "", "cookie-token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: cookieName, Value: tt.cookie})
+			}
+			if tt.query != "" {
+				q := r.URL.Query()
+				q.Set("jwt", tt.query)
+				r.URL.RawQuery = q.Encode()
+			}
+
+			got, err := extractToken(r, cookieName)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("extractToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/devtron-labs/authenticator/oidc"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// TestVerifyTokenEmptyIssuerIsUnknown is a regression test: a token with no
+// "iss" claim must never be routed into the IDP-verification branch, even
+// when no OIDC provider is configured (where idpIssuer() also returns "").
+// Previously this matched claims.Issuer == "" against mgr.idpIssuer() == ""
+// and panicked dereferencing the nil OIDCConfig.
+func TestVerifyTokenEmptyIssuerIsUnknown(t *testing.T) {
+	settings := &oidc.Settings{
+		SigningKeys: []oidc.SigningKey{
+			{ID: "active", Algorithm: oidc.HS256, Secret: []byte("session-secret")},
+		},
+		ActiveKeyID: "active",
+		// OIDCConfig intentionally left nil.
+	}
+	mgr, err := NewSessionManager(settings, "")
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice"})
+	tokenString, err := token.SignedString([]byte("whatever"))
+	if err != nil {
+		t.Fatalf("signing token with no iss claim: %v", err)
+	}
+
+	if _, err := mgr.VerifyToken(tokenString); err == nil {
+		t.Fatal("expected VerifyToken to reject a token with no iss claim as unknown, not panic or accept it")
+	}
+}
@@ -0,0 +1,89 @@
+package oidc
+
+import "time"
+
+// OIDCConfig holds the configuration needed to talk to the upstream OIDC/OAuth2
+// identity provider (Dex, or any other compliant IdP).
+type OIDCConfig struct {
+	Name            string
+	Issuer          string
+	ClientID        string
+	ClientSecret    string
+	RequestedScopes []string
+}
+
+// SigningAlgorithm identifies the JWS algorithm a SigningKey is used with.
+type SigningAlgorithm string
+
+const (
+	HS256 SigningAlgorithm = "HS256"
+	RS256 SigningAlgorithm = "RS256"
+	EdDSA SigningAlgorithm = "EdDSA"
+)
+
+// SigningKey is a single named key in the session manager's key set. Which of
+// Secret/PrivateKeyPEM/PublicKeyPEM are populated depends on Algorithm: Secret
+// for HS256, PrivateKeyPEM and/or PublicKeyPEM for RS256 or EdDSA.
+type SigningKey struct {
+	// ID is stamped into the "kid" header of tokens signed with this key, and
+	// used to look the key back up when verifying.
+	ID string
+
+	Algorithm SigningAlgorithm
+
+	// Secret is the HMAC key, only set when Algorithm is HS256.
+	Secret []byte
+
+	// PrivateKeyPEM/PublicKeyPEM hold PEM-encoded RSA or Ed25519 key material,
+	// only set when Algorithm is RS256 or EdDSA. Verifiers that only ever
+	// verify (never sign) may populate just PublicKeyPEM.
+	PrivateKeyPEM []byte
+	PublicKeyPEM  []byte
+}
+
+// IntrospectionConfig points at an OAuth2 introspection endpoint (RFC 7662)
+// used to validate opaque bearer tokens that aren't JWTs at all, e.g. tokens
+// minted by an upstream IdP that only supports reference tokens.
+type IntrospectionConfig struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	Timeout      time.Duration
+	// CacheTTL controls how long a positive introspection result is cached by
+	// token hash. Zero disables caching.
+	CacheTTL time.Duration
+}
+
+// ExternalServiceConfig describes a downstream service SessionManager can
+// mint scoped tokens for (the EXTJWT integration pattern): its own signing
+// key, never the main session key, plus optional issuer/audience overrides.
+type ExternalServiceConfig struct {
+	SigningKey SigningKey
+	Issuer     string
+	Audience   string
+}
+
+// Settings is the subset of Argo CD settings the session manager depends on.
+type Settings struct {
+	OIDCConfig *OIDCConfig
+
+	// SigningKeys is the full set of keys the session manager knows about.
+	// Tokens are verified against any key in the set, keyed by "kid", so that
+	// keys can be rotated in without invalidating tokens signed by the
+	// previous active key.
+	SigningKeys []SigningKey
+
+	// ActiveKeyID is the ID of the SigningKeys entry newly issued tokens are
+	// signed with.
+	ActiveKeyID string
+
+	// Introspection, if set, lets VerifyToken validate opaque bearer tokens
+	// against an OAuth2 introspection endpoint instead of rejecting them
+	// outright as unparseable JWTs.
+	Introspection *IntrospectionConfig
+
+	// ExternalServices names the downstream services SessionManager.
+	// CreateForService can mint scoped tokens for, keyed by service name
+	// (e.g. "image-host", "call-host").
+	ExternalServices map[string]ExternalServiceConfig
+}